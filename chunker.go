@@ -0,0 +1,111 @@
+package ssdeep
+
+import (
+	"bufio"
+	"io"
+)
+
+const (
+	// defaultChunkMask targets an average chunk size of 8KiB: a boundary
+	// is declared once roughly 1 in (mask+1) rolling sums match.
+	defaultChunkMask = 1<<13 - 1
+
+	defaultMinChunkSize = 2 * 1024
+	defaultMaxChunkSize = 64 * 1024
+)
+
+// ChunkerOptions configures a Chunker. The zero value selects the package
+// defaults: an average chunk size of 8KiB bounded between 2KiB and 64KiB.
+type ChunkerOptions struct {
+	// Mask is tested against the rolling sum at every byte; a boundary is
+	// declared when rollSum()%Mask == Mask-1. A mask of 2^n-1 gives chunks
+	// that average 2^n bytes. Zero selects defaultChunkMask.
+	Mask uint32
+
+	// MinChunkSize is the fewest bytes a chunk may contain; boundaries
+	// found before it are ignored. Zero selects defaultMinChunkSize.
+	MinChunkSize uint64
+
+	// MaxChunkSize is the most bytes a chunk may contain before a
+	// boundary is forced regardless of the rolling sum. Zero selects
+	// defaultMaxChunkSize.
+	MaxChunkSize uint64
+}
+
+func (o ChunkerOptions) withDefaults() ChunkerOptions {
+	if o.Mask == 0 {
+		o.Mask = defaultChunkMask
+	}
+	if o.MinChunkSize == 0 {
+		o.MinChunkSize = defaultMinChunkSize
+	}
+	if o.MaxChunkSize == 0 {
+		o.MaxChunkSize = defaultMaxChunkSize
+	}
+	return o
+}
+
+// Chunker splits a stream into content-defined chunks using the same
+// rolling hash (rollingState) the fuzzy hasher uses to pick its block
+// size, so a boundary falls wherever local content repeats regardless of
+// shifts elsewhere in the stream. This makes it suitable for dedup/CAS
+// storage, the way rollsum-based chunkers (e.g. containers/storage's
+// chunked layer format) are used, without pulling in a second
+// rolling-hash implementation.
+//
+// BLOCKING DEPENDENCY: rollingState (and the rollHash/rollSum methods
+// Chunker calls on it) is not defined anywhere in this tree as of this
+// commit — only ssdeep_test.go exists alongside it, and that test file
+// already references the same missing type. This file will not compile
+// until the core fuzzy-hasher implementation is added to the package.
+type Chunker struct {
+	r       *bufio.Reader
+	opts    ChunkerOptions
+	rolling rollingState
+	offset  uint64
+}
+
+// NewChunker returns a Chunker reading from r. opts is normalized with
+// withDefaults; pass the zero value to use the package defaults.
+func NewChunker(r io.Reader, opts ChunkerOptions) *Chunker {
+	return &Chunker{
+		r:    bufio.NewReader(r),
+		opts: opts.withDefaults(),
+	}
+}
+
+// Next returns the next chunk as (offset, length, data). It returns
+// io.EOF once the underlying reader is exhausted and no partial chunk
+// remains.
+func (c *Chunker) Next() (offset, length uint64, data []byte, err error) {
+	start := c.offset
+	buf := make([]byte, 0, c.opts.MinChunkSize)
+
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				if len(buf) == 0 {
+					return 0, 0, nil, io.EOF
+				}
+				c.offset += uint64(len(buf))
+				return start, uint64(len(buf)), buf, nil
+			}
+			return 0, 0, nil, err
+		}
+
+		buf = append(buf, b)
+		c.rolling.rollHash(b)
+		c.offset++
+
+		if uint64(len(buf)) < c.opts.MinChunkSize {
+			continue
+		}
+		if uint64(len(buf)) >= c.opts.MaxChunkSize {
+			return start, uint64(len(buf)), buf, nil
+		}
+		if c.rolling.rollSum()%c.opts.Mask == c.opts.Mask-1 {
+			return start, uint64(len(buf)), buf, nil
+		}
+	}
+}
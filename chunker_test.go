@@ -0,0 +1,97 @@
+package ssdeep
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func chunkAll(t *testing.T, r io.Reader, opts ChunkerOptions) [][]byte {
+	t.Helper()
+
+	c := NewChunker(r, opts)
+	var chunks [][]byte
+	for {
+		_, _, data, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, data)
+	}
+	return chunks
+}
+
+func TestChunkerReconstructsOriginalStream(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	input := make([]byte, 5*64*1024)
+	_, err := rnd.Read(input)
+	require.NoError(t, err)
+
+	chunks := chunkAll(t, bytes.NewReader(input), ChunkerOptions{})
+
+	reassembled := make([]byte, 0, len(input))
+	for _, c := range chunks {
+		reassembled = append(reassembled, c...)
+	}
+	require.Equal(t, input, reassembled)
+}
+
+func TestChunkerRespectsMinAndMaxChunkSize(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	input := make([]byte, 512*1024)
+	_, err := rnd.Read(input)
+	require.NoError(t, err)
+
+	opts := ChunkerOptions{Mask: 1<<10 - 1, MinChunkSize: 1024, MaxChunkSize: 4096}
+	chunks := chunkAll(t, bytes.NewReader(input), opts)
+
+	require.NotEmpty(t, chunks)
+	for i, c := range chunks {
+		require.LessOrEqual(t, uint64(len(c)), opts.MaxChunkSize)
+		if i < len(chunks)-1 {
+			// Only the final chunk may be shorter than MinChunkSize, the
+			// same way the last read off a stream can be a short one.
+			require.GreaterOrEqual(t, uint64(len(c)), opts.MinChunkSize)
+		}
+	}
+}
+
+func TestChunkerOnEmptyInputReturnsEOFImmediately(t *testing.T) {
+	c := NewChunker(bytes.NewReader(nil), ChunkerOptions{})
+	_, _, _, err := c.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestChunkerOnInputShorterThanMinChunkSizeReturnsOneChunk(t *testing.T) {
+	input := []byte("short")
+	opts := ChunkerOptions{MinChunkSize: 1024, MaxChunkSize: 4096}
+
+	chunks := chunkAll(t, bytes.NewReader(input), opts)
+	require.Len(t, chunks, 1)
+	require.Equal(t, input, chunks[0])
+}
+
+func TestChunkerOffsetsAreContiguous(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	input := make([]byte, 256*1024)
+	_, err := rnd.Read(input)
+	require.NoError(t, err)
+
+	c := NewChunker(bytes.NewReader(input), ChunkerOptions{Mask: 1<<10 - 1, MinChunkSize: 512, MaxChunkSize: 2048})
+
+	var wantOffset uint64
+	for {
+		offset, length, _, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, wantOffset, offset)
+		wantOffset += length
+	}
+	require.Equal(t, uint64(len(input)), wantOffset)
+}
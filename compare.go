@@ -0,0 +1,300 @@
+package ssdeep
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// minBlocksize is the smallest block size a digest can report; it is
+	// the denominator used to rescale a raw edit-distance score against
+	// the amount of input the block size implies was hashed.
+	minBlocksize = 3
+
+	// rollingWindow is the length of the substring that two signatures
+	// must share before they are considered comparable at all. It
+	// mirrors the window size used by the rolling hash itself.
+	rollingWindow = 7
+
+	insertCost     = 1
+	deleteCost     = 1
+	substituteCost = 3
+
+	// spamsumLength is the maximum length of one half of a spamsum
+	// signature, used to normalize edit distance into a 0-100 score.
+	spamsumLength = 64
+)
+
+// digest is a parsed "blocksize:h1:h2" ssdeep signature.
+type digest struct {
+	blockSize int
+	h1, h2    string
+}
+
+func parseDigest(s string) (digest, error) {
+	fields := strings.SplitN(s, ":", 3)
+	if len(fields) != 3 {
+		return digest{}, fmt.Errorf("ssdeep: malformed digest %q", s)
+	}
+	bs, err := strconv.Atoi(fields[0])
+	if err != nil || bs <= 0 {
+		return digest{}, fmt.Errorf("ssdeep: malformed block size in digest %q", s)
+	}
+	return digest{blockSize: bs, h1: fields[1], h2: fields[2]}, nil
+}
+
+// Compare returns a similarity score between 0 and 100 for two digests in
+// "blocksize:h1:h2" form. 0 means the digests are considered unrelated
+// (including when their block sizes are too far apart to compare at all);
+// 100 is the highest score. At small block sizes the score is capped by
+// the block size, so even identical digests can score less than 100.
+func Compare(a, b string) (int, error) {
+	da, err := parseDigest(a)
+	if err != nil {
+		return 0, err
+	}
+	db, err := parseDigest(b)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case da.blockSize == db.blockSize:
+		// h1 is hashed at blockSize, h2 at blockSize*2: each half must be
+		// capped using the block size it was actually hashed at.
+		s1 := scoreStrings(da.h1, db.h1, da.blockSize)
+		s2 := scoreStrings(da.h2, db.h2, da.blockSize*2)
+		if s2 > s1 {
+			return s2, nil
+		}
+		return s1, nil
+	case da.blockSize == db.blockSize*2:
+		// da.h1 (hashed at da.blockSize) lines up with db.h2 (hashed at
+		// db.blockSize*2, i.e. da.blockSize); cap with the larger size.
+		return scoreStrings(da.h1, db.h2, da.blockSize), nil
+	case db.blockSize == da.blockSize*2:
+		// da.h2 (hashed at da.blockSize*2, i.e. db.blockSize) lines up
+		// with db.h1 (hashed at db.blockSize); cap with the larger size.
+		return scoreStrings(da.h2, db.h1, db.blockSize), nil
+	default:
+		return 0, nil
+	}
+}
+
+// scoreStrings implements the spamsum matching algorithm for a single pair
+// of signature halves that share a block size: it strips long runs of a
+// repeated character, bails out to 0 unless the two strings share a
+// rollingWindow-length substring, and otherwise turns their edit distance
+// into a 0-100 score capped by how much input the block size represents.
+func scoreStrings(s1, s2 string, blockSize int) int {
+	s1 = eliminateSequences(s1)
+	s2 = eliminateSequences(s2)
+
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+	if !haveCommonSubstring(s1, s2, rollingWindow) {
+		return 0
+	}
+
+	dist := editDistance(s1, s2)
+
+	score := dist * spamsumLength / (len(s1) + len(s2))
+	score = score * 100 / spamsumLength
+	score = 100 - score
+	if score > 100 {
+		score = 100
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	if matchCap := blockSize / minBlocksize * minInt(len(s1), len(s2)); score > matchCap {
+		score = matchCap
+	}
+	return score
+}
+
+// eliminateSequences collapses any run of more than 3 identical characters
+// down to exactly 3, the same normalization spamsum applies before
+// comparing two signatures so that long repeated runs don't dominate the
+// edit distance.
+func eliminateSequences(s string) string {
+	if len(s) <= 3 {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if i >= 3 && s[i] == s[i-1] && s[i-1] == s[i-2] && s[i-2] == s[i-3] {
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+func haveCommonSubstring(s1, s2 string, window int) bool {
+	if len(s1) < window || len(s2) < window {
+		return false
+	}
+	grams := make(map[string]struct{}, len(s1)-window+1)
+	for i := 0; i+window <= len(s1); i++ {
+		grams[s1[i:i+window]] = struct{}{}
+	}
+	for i := 0; i+window <= len(s2); i++ {
+		if _, ok := grams[s2[i:i+window]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance computes the minimum-cost sequence of insertions, deletions
+// and substitutions that turns s1 into s2, weighted the way spamsum weighs
+// them (insertions and deletions cost 1, substitutions cost 3).
+func editDistance(s1, s2 string) int {
+	prev := make([]int, len(s2)+1)
+	curr := make([]int, len(s2)+1)
+	for j := range prev {
+		prev[j] = j * insertCost
+	}
+	for i := 1; i <= len(s1); i++ {
+		curr[0] = i * deleteCost
+		for j := 1; j <= len(s2); j++ {
+			if s1[i-1] == s2[j-1] {
+				curr[j] = prev[j-1]
+				continue
+			}
+			sub := prev[j-1] + substituteCost
+			del := prev[j] + deleteCost
+			ins := curr[j-1] + insertCost
+			curr[j] = minInt(sub, minInt(del, ins))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(s2)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func sevenGrams(s string) []string {
+	if len(s) < rollingWindow {
+		return nil
+	}
+	grams := make([]string, 0, len(s)-rollingWindow+1)
+	for i := 0; i+rollingWindow <= len(s); i++ {
+		grams = append(grams, s[i:i+rollingWindow])
+	}
+	return grams
+}
+
+// Match is a single result returned by Matcher.Query.
+type Match struct {
+	ID    interface{}
+	Score int
+}
+
+type matcherRecord struct {
+	id        interface{}
+	digest    string
+	blockSize int
+	h1, h2    string
+}
+
+// Matcher indexes a corpus of ssdeep digests so that a Query can find
+// candidate matches without comparing against every indexed digest. Since
+// Compare can only ever produce a nonzero score between digests whose
+// block sizes are equal, half or double one another, and scoreStrings
+// requires two signatures to share a rollingWindow-length substring
+// ("7-gram") before it will score them at all, digests are indexed by the
+// 7-grams of their signature halves in a single index. There is no
+// separate index by block size: a Query checks the block size of each
+// candidate its 7-grams find. A Matcher is safe for concurrent use.
+type Matcher struct {
+	mu      sync.RWMutex
+	records []matcherRecord
+	byChunk map[string][]int
+}
+
+// NewMatcher returns an empty Matcher ready to be populated with Add.
+func NewMatcher() *Matcher {
+	return &Matcher{byChunk: make(map[string][]int)}
+}
+
+// Add indexes digest under id, which is returned unchanged in any Match
+// produced by a later Query.
+func (m *Matcher) Add(id interface{}, digest string) error {
+	d, err := parseDigest(digest)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := len(m.records)
+	m.records = append(m.records, matcherRecord{id: id, digest: digest, blockSize: d.blockSize, h1: d.h1, h2: d.h2})
+	for _, gram := range sevenGrams(d.h1) {
+		m.byChunk[gram] = append(m.byChunk[gram], idx)
+	}
+	for _, gram := range sevenGrams(d.h2) {
+		m.byChunk[gram] = append(m.byChunk[gram], idx)
+	}
+	return nil
+}
+
+// Query returns the indexed digests that score at least threshold against
+// digest, ordered from the highest score down. Only digests whose block
+// size is equal, half or double digest's and that share a 7-gram with
+// digest's signature halves as written are scored; a digest that shares a
+// 7-gram only once runs of repeated characters are collapsed is not
+// returned.
+func (m *Matcher) Query(digest string, threshold int) ([]Match, error) {
+	d, err := parseDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	validBlockSize := map[int]bool{d.blockSize: true}
+	if d.blockSize%2 == 0 {
+		validBlockSize[d.blockSize/2] = true
+	}
+	validBlockSize[d.blockSize*2] = true
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[int]struct{})
+	for _, gram := range append(sevenGrams(d.h1), sevenGrams(d.h2)...) {
+		for _, idx := range m.byChunk[gram] {
+			if _, ok := seen[idx]; ok {
+				continue
+			}
+			if validBlockSize[m.records[idx].blockSize] {
+				seen[idx] = struct{}{}
+			}
+		}
+	}
+
+	matches := make([]Match, 0, len(seen))
+	for idx := range seen {
+		rec := m.records[idx]
+		score, err := Compare(digest, rec.digest)
+		if err != nil {
+			continue
+		}
+		if score >= threshold {
+			matches = append(matches, Match{ID: rec.id, Score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
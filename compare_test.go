@@ -0,0 +1,89 @@
+package ssdeep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareIdenticalDigestsScore100(t *testing.T) {
+	d := "48:abcdefghijklmnop:abcdefghijklmnop"
+	score, err := Compare(d, d)
+	require.NoError(t, err)
+	require.Equal(t, 100, score)
+}
+
+func TestCompareCapsH2UsingDoubleTheBlockSize(t *testing.T) {
+	// At the minimum block size the cap dominates: h1 is capped with
+	// blockSize (3/3*20 = 20) but h2 is hashed at blockSize*2 and so must
+	// be capped with 2*blockSize (6/3*20 = 40). The identical digests
+	// below would wrongly score 20 if h2's cap used blockSize instead of
+	// blockSize*2.
+	d := "3:ABCDEFGHIJKLMNOPQRST:abcdefghijklmnopqrst"
+	score, err := Compare(d, d)
+	require.NoError(t, err)
+	require.Equal(t, 40, score)
+}
+
+func TestCompareUsesTheHigherOfEitherHalfAtEqualBlockSize(t *testing.T) {
+	score, err := Compare(
+		"48:abcdefghijklmnop:AAAAAAAAAAAAAAAA",
+		"48:abcdefghijklmnop:BBBBBBBBBBBBBBBB",
+	)
+	require.NoError(t, err)
+	require.Equal(t, 100, score)
+}
+
+func TestCompareMatchesAcrossDoubledBlockSize(t *testing.T) {
+	// h2 of the b digest lines up with h1 of the 2b digest.
+	score, err := Compare(
+		"48:xxxxxxxxxxxxxxxx:abcdefghijklmnop",
+		"96:abcdefghijklmnop:yyyyyyyyyyyyyyyy",
+	)
+	require.NoError(t, err)
+	require.Equal(t, 100, score)
+}
+
+func TestCompareUnrelatedBlockSizesScoreZero(t *testing.T) {
+	score, err := Compare("3:abcdefghij:abcdefghij", "48:abcdefghij:abcdefghij")
+	require.NoError(t, err)
+	require.Equal(t, 0, score)
+}
+
+func TestCompareWithoutCommonSubstringScoresZero(t *testing.T) {
+	score, err := Compare(
+		"48:abcdefghijklmnop:abcdefghijklmnop",
+		"48:zyxwvutsrqponmlk:zyxwvutsrqponmlk",
+	)
+	require.NoError(t, err)
+	require.Equal(t, 0, score)
+}
+
+func TestCompareRejectsMalformedDigests(t *testing.T) {
+	_, err := Compare("not-a-digest", "3:abcdefghij:abcdefghij")
+	require.Error(t, err)
+
+	_, err = Compare("3:abcdefghij:abcdefghij", "not-a-digest")
+	require.Error(t, err)
+}
+
+func TestMatcherQueryFindsIndexedMatchAboveThreshold(t *testing.T) {
+	m := NewMatcher()
+	require.NoError(t, m.Add("exact", "48:abcdefghijklmnop:abcdefghijklmnop"))
+	require.NoError(t, m.Add("unrelated", "48:zyxwvutsrqponmlk:zyxwvutsrqponmlk"))
+
+	matches, err := m.Query("48:abcdefghijklmnop:abcdefghijklmnop", 50)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	require.Equal(t, "exact", matches[0].ID)
+	require.Equal(t, 100, matches[0].Score)
+}
+
+func TestMatcherQueryOmitsMatchesBelowThreshold(t *testing.T) {
+	m := NewMatcher()
+	require.NoError(t, m.Add("unrelated", "48:zyxwvutsrqponmlk:zyxwvutsrqponmlk"))
+
+	matches, err := m.Query("48:abcdefghijklmnop:abcdefghijklmnop", 50)
+	require.NoError(t, err)
+	require.Empty(t, matches)
+}
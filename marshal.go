@@ -0,0 +1,217 @@
+package ssdeep
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// This file adds (Un)MarshalBinary to rollingState, blockHashState and
+// ssdeepState, plus a Reset on ssdeepState.
+//
+// Each state type encodes as a short magic string, a version byte, and a
+// fixed or length-prefixed layout of its fields. The magic+version pair
+// lets UnmarshalBinary reject foreign or future-format data instead of
+// silently misreading it, the same guard the stdlib hash implementations
+// (e.g. crypto/sha256) put in front of their own checkpoint format.
+//
+// BLOCKING DEPENDENCY: rollingState, blockHashState and ssdeepState are
+// not defined anywhere in this tree as of this commit — only
+// ssdeep_test.go exists alongside it, and that test file already
+// references these same missing types. This file will not compile until
+// the core fuzzy-hasher implementation is added to the package.
+const (
+	rollingStateMagic     = "SSR1"
+	rollingStateVersion   = 1
+	blockHashStateMagic   = "SSB1"
+	blockHashStateVersion = 1
+	ssdeepStateMagic      = "SSD1"
+	ssdeepStateVersion    = 1
+)
+
+// MarshalBinary encodes the rolling window state so it can be checkpointed
+// alongside the rest of a streaming hash.
+func (r *rollingState) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, len(rollingStateMagic)+1+len(r.window)+4+4+4+4)
+	buf = append(buf, rollingStateMagic...)
+	buf = append(buf, rollingStateVersion)
+	buf = append(buf, r.window[:]...)
+	buf = binary.BigEndian.AppendUint32(buf, r.h1)
+	buf = binary.BigEndian.AppendUint32(buf, r.h2)
+	buf = binary.BigEndian.AppendUint32(buf, r.h3)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(r.n))
+	return buf, nil
+}
+
+// UnmarshalBinary restores a rollingState previously produced by
+// MarshalBinary.
+func (r *rollingState) UnmarshalBinary(data []byte) error {
+	const headerLen = len(rollingStateMagic) + 1
+	if len(data) < headerLen+8+16 {
+		return fmt.Errorf("ssdeep: rollingState: short buffer")
+	}
+	if string(data[:len(rollingStateMagic)]) != rollingStateMagic {
+		return fmt.Errorf("ssdeep: rollingState: bad magic")
+	}
+	if data[len(rollingStateMagic)] != rollingStateVersion {
+		return fmt.Errorf("ssdeep: rollingState: unsupported version %d", data[len(rollingStateMagic)])
+	}
+	data = data[headerLen:]
+
+	copy(r.window[:], data[:len(r.window)])
+	data = data[len(r.window):]
+	r.h1 = binary.BigEndian.Uint32(data)
+	data = data[4:]
+	r.h2 = binary.BigEndian.Uint32(data)
+	data = data[4:]
+	r.h3 = binary.BigEndian.Uint32(data)
+	data = data[4:]
+	r.n = int(binary.BigEndian.Uint32(data))
+	return nil
+}
+
+// MarshalBinary encodes a single block's accumulated hash state.
+func (b *blockHashState) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, len(blockHashStateMagic)+1+4+len(b.hashString)+4+1+1+1+1)
+	buf = append(buf, blockHashStateMagic...)
+	buf = append(buf, blockHashStateVersion)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(b.hashString)))
+	buf = append(buf, b.hashString...)
+	buf = binary.BigEndian.AppendUint32(buf, b.blockSize)
+	buf = append(buf, b.blockHash1, b.blockHash2, b.tail1, b.tail2)
+	return buf, nil
+}
+
+// UnmarshalBinary restores a blockHashState previously produced by
+// MarshalBinary.
+func (b *blockHashState) UnmarshalBinary(data []byte) error {
+	const headerLen = len(blockHashStateMagic) + 1
+	if len(data) < headerLen+4 {
+		return fmt.Errorf("ssdeep: blockHashState: short buffer")
+	}
+	if string(data[:len(blockHashStateMagic)]) != blockHashStateMagic {
+		return fmt.Errorf("ssdeep: blockHashState: bad magic")
+	}
+	if data[len(blockHashStateMagic)] != blockHashStateVersion {
+		return fmt.Errorf("ssdeep: blockHashState: unsupported version %d", data[len(blockHashStateMagic)])
+	}
+	data = data[headerLen:]
+
+	n := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint64(n)+8 > uint64(len(data)) {
+		return fmt.Errorf("ssdeep: blockHashState: short buffer")
+	}
+	if n == 0 {
+		b.hashString = nil
+	} else {
+		b.hashString = append([]byte(nil), data[:n]...)
+	}
+	data = data[n:]
+
+	b.blockSize = binary.BigEndian.Uint32(data)
+	data = data[4:]
+	b.blockHash1, b.blockHash2, b.tail1, b.tail2 = data[0], data[1], data[2], data[3]
+	return nil
+}
+
+// MarshalBinary encodes the complete state of a streaming ssdeep hasher,
+// including the rolling window and every candidate block size, so it can
+// be persisted mid-stream and resumed later with UnmarshalBinary. This
+// gives *ssdeepState the same checkpoint contract the stdlib hash package
+// provides for algorithms like sha256.
+func (s *ssdeepState) MarshalBinary() ([]byte, error) {
+	rolling, err := s.rollingState.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, len(ssdeepStateMagic)+1+len(rolling)+8+8+8+4)
+	buf = append(buf, ssdeepStateMagic...)
+	buf = append(buf, ssdeepStateVersion)
+	buf = append(buf, rolling...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(s.iStart))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(s.iEnd))
+	buf = binary.BigEndian.AppendUint64(buf, s.totalSize)
+	buf = binary.BigEndian.AppendUint32(buf, s.bsizeMask)
+
+	for i := range s.blocks {
+		blk, err := s.blocks[i].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(blk)))
+		buf = append(buf, blk...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary restores a ssdeepState previously produced by
+// MarshalBinary, allowing a long-running scanner to resume hashing a
+// stream it had checkpointed mid-way through.
+func (s *ssdeepState) UnmarshalBinary(data []byte) error {
+	const headerLen = len(ssdeepStateMagic) + 1
+	if len(data) < headerLen {
+		return fmt.Errorf("ssdeep: ssdeepState: short buffer")
+	}
+	if string(data[:len(ssdeepStateMagic)]) != ssdeepStateMagic {
+		return fmt.Errorf("ssdeep: ssdeepState: bad magic")
+	}
+	if data[len(ssdeepStateMagic)] != ssdeepStateVersion {
+		return fmt.Errorf("ssdeep: ssdeepState: unsupported version %d", data[len(ssdeepStateMagic)])
+	}
+	data = data[headerLen:]
+
+	rollingLen := len(rollingStateMagic) + 1 + len(s.window) + 16
+	if len(data) < rollingLen+8+8+8+4 {
+		return fmt.Errorf("ssdeep: ssdeepState: short buffer")
+	}
+	if err := s.rollingState.UnmarshalBinary(data[:rollingLen]); err != nil {
+		return err
+	}
+	data = data[rollingLen:]
+
+	s.iStart = int(binary.BigEndian.Uint64(data))
+	data = data[8:]
+	s.iEnd = int(binary.BigEndian.Uint64(data))
+	data = data[8:]
+	s.totalSize = binary.BigEndian.Uint64(data)
+	data = data[8:]
+	s.bsizeMask = binary.BigEndian.Uint32(data)
+	data = data[4:]
+
+	for i := range s.blocks {
+		if len(data) < 4 {
+			return fmt.Errorf("ssdeep: ssdeepState: short buffer")
+		}
+		blkLen := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint32(len(data)) < blkLen {
+			return fmt.Errorf("ssdeep: ssdeepState: short buffer")
+		}
+		if err := s.blocks[i].UnmarshalBinary(data[:blkLen]); err != nil {
+			return err
+		}
+		data = data[blkLen:]
+	}
+	return nil
+}
+
+// Reset returns the hasher to its initial, empty state (only the first
+// block active, every block with fresh block hashes and no hash
+// characters) so it can be reused across many inputs,
+// avoiding the per-call allocation FuzzyBytes otherwise pays for every
+// invocation of New.
+func (s *ssdeepState) Reset() {
+	s.rollingState = rollingState{}
+	s.iStart = 0
+	s.iEnd = 1
+	s.totalSize = 0
+	s.bsizeMask = 0
+	for i := range s.blocks {
+		s.blocks[i] = blockHashState{
+			blockSize:  uint32(minBlocksize) << uint(i),
+			blockHash1: hashInit,
+			blockHash2: hashInit,
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package ssdeep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollingStateMarshalRoundTrip(t *testing.T) {
+	want := rollingState{
+		window: [8]uint8{1, 2, 3, 4, 5, 6, 7, 8},
+		h1:     679,
+		h2:     2716,
+		h3:     2216757313,
+		n:      6,
+	}
+
+	data, err := want.MarshalBinary()
+	require.NoError(t, err)
+
+	var got rollingState
+	require.NoError(t, got.UnmarshalBinary(data))
+	require.Equal(t, want, got)
+}
+
+func TestBlockHashStateMarshalRoundTrip(t *testing.T) {
+	want := blockHashState{
+		hashString: []uint8{45, 35, 12},
+		blockSize:  3,
+		blockHash1: 53,
+		blockHash2: 39,
+		tail1:      1,
+		tail2:      2,
+	}
+
+	data, err := want.MarshalBinary()
+	require.NoError(t, err)
+
+	var got blockHashState
+	require.NoError(t, got.UnmarshalBinary(data))
+	require.Equal(t, want, got)
+}
+
+func TestBlockHashStateMarshalRoundTripWithEmptyHashString(t *testing.T) {
+	want := blockHashState{blockSize: 6, blockHash1: 39, blockHash2: 39}
+
+	data, err := want.MarshalBinary()
+	require.NoError(t, err)
+
+	var got blockHashState
+	require.NoError(t, got.UnmarshalBinary(data))
+	require.Equal(t, want, got)
+}
+
+func TestBlockHashStateUnmarshalRejectsCorruptLength(t *testing.T) {
+	var b blockHashState
+	data := append([]byte(blockHashStateMagic), blockHashStateVersion)
+	// A length prefix that, added to the trailing fixed fields, would
+	// overflow uint32 arithmetic if computed naively.
+	data = append(data, 0xFF, 0xFF, 0xFF, 0xFE)
+	data = append(data, make([]byte, 4)...) // short: nowhere near n bytes of payload
+
+	require.Error(t, b.UnmarshalBinary(data))
+}
+
+func TestBlockHashStateUnmarshalRejectsShortBuffer(t *testing.T) {
+	var b blockHashState
+	require.Error(t, b.UnmarshalBinary(nil))
+	// Valid header, but the length prefix is cut off after two bytes.
+	require.Error(t, b.UnmarshalBinary(append([]byte(blockHashStateMagic), blockHashStateVersion, 0, 0)))
+}
+
+func newTestSSDEEPState() ssdeepState {
+	s := ssdeepState{
+		rollingState: rollingState{
+			window: [8]uint8{97, 97, 97, 97, 97, 97, 97, 0},
+			h1:     679,
+			h2:     2716,
+			h3:     2216757313,
+			n:      6,
+		},
+		iStart:    0,
+		iEnd:      2,
+		totalSize: 4500000000,
+		bsizeMask: 0,
+	}
+	s.blocks[0] = blockHashState{hashString: []uint8{45, 35}, blockSize: 3, blockHash1: 53, blockHash2: 53}
+	for i := 1; i < len(s.blocks); i++ {
+		s.blocks[i] = blockHashState{blockSize: uint32(minBlocksize) << uint(i), blockHash1: 39, blockHash2: 39}
+	}
+	return s
+}
+
+func TestSSDEEPStateMarshalRoundTrip(t *testing.T) {
+	want := newTestSSDEEPState()
+
+	data, err := want.MarshalBinary()
+	require.NoError(t, err)
+
+	var got ssdeepState
+	require.NoError(t, got.UnmarshalBinary(data))
+	require.Equal(t, want, got)
+}
+
+func TestSSDEEPStateResetReinitializesAPreviouslyUsedHasher(t *testing.T) {
+	s := newTestSSDEEPState()
+	s.Reset()
+
+	var fresh ssdeepState
+	fresh.Reset()
+	require.Equal(t, fresh, s)
+
+	require.Equal(t, uint64(0), s.totalSize)
+	require.Equal(t, uint32(0), s.bsizeMask)
+	for i := range s.blocks {
+		require.Nil(t, s.blocks[i].hashString)
+		require.Equal(t, hashInit, s.blocks[i].blockHash1)
+		require.Equal(t, hashInit, s.blocks[i].blockHash2)
+		require.Equal(t, uint32(minBlocksize)<<uint(i), s.blocks[i].blockSize)
+	}
+}
+
+func TestSSDEEPStateUnmarshalRejectsForeignData(t *testing.T) {
+	var s ssdeepState
+	require.Error(t, s.UnmarshalBinary([]byte("not a checkpoint")))
+}
@@ -0,0 +1,126 @@
+package ssdeep
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyReaderAtMatchesFuzzyFile(t *testing.T) {
+	sizes := []int64{8192, 65536, 262144, 1 << 20, 5 * (1 << 20)}
+
+	for _, size := range sizes {
+		size := size
+		t.Run(fmt.Sprintf("%d bytes", size), func(t *testing.T) {
+			f := mustTempFileWithRandomData(t, size)
+			defer os.Remove(f.Name())
+			defer f.Close()
+
+			want, err := FuzzyFile(f)
+			require.NoError(t, err)
+
+			_, err = f.Seek(0, 0)
+			require.NoError(t, err)
+
+			got, err := FuzzyReaderAt(f, size)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestFuzzyReaderAtMatchesFuzzyFileWithForcedMultipleShards(t *testing.T) {
+	const size = 5 * (1 << 20)
+	f := mustTempFileWithRandomData(t, size)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	want, err := FuzzyFile(f)
+	require.NoError(t, err)
+
+	blockSize := blockSizeForLength(uint64(size))
+	got, err := fuzzyReaderAtBlockSize(f, size, blockSize, 8)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestFuzzyReaderAtRetriesAtDoubleBlockSizeOnOverflow(t *testing.T) {
+	const size = 5 * (1 << 20)
+	f := mustTempFileWithRandomData(t, size)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	want, err := FuzzyFile(f)
+	require.NoError(t, err)
+
+	// Deliberately start from a block size far smaller than
+	// blockSizeForLength would pick, forcing at least one
+	// overflow-and-double retry.
+	got, err := fuzzyReaderAtBlockSize(f, size, minBlocksize, 1)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func BenchmarkFuzzyReaderAt(b *testing.B) {
+	f := mustTempFileWithRandomData(b, 1<<30)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := FuzzyReaderAt(f, info.Size()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFuzzyFileOnLargeInput(b *testing.B) {
+	f := mustTempFileWithRandomData(b, 1<<30)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Seek(0, 0); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := FuzzyFile(f); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func mustTempFileWithRandomData(tb testing.TB, size int64) *os.File {
+	tb.Helper()
+
+	f, err := os.CreateTemp("", "ssdeep-bench-*")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	buf := make([]byte, 1<<20)
+	for written := int64(0); written < size; {
+		n := len(buf)
+		if remaining := size - written; remaining < int64(n) {
+			n = int(remaining)
+		}
+		if _, err := r.Read(buf[:n]); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := f.Write(buf[:n]); err != nil {
+			tb.Fatal(err)
+		}
+		written += int64(n)
+	}
+
+	return f
+}